@@ -0,0 +1,77 @@
+package solidity
+
+import (
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+)
+
+// AccessTuple is one entry of an EIP-2930 access list: an address the
+// transaction declares it will touch, plus the storage slots within it.
+type AccessTuple struct {
+	Address     common.Address
+	StorageKeys []hash.Hash256
+}
+
+// AccessList is a transaction-declared list of addresses and storage slots
+// that should be treated as warm for the whole call, mirroring EIP-2930.
+type AccessList []AccessTuple
+
+// AddressInAccessList returns whether addr is warm
+func (sd *StateDB) AddressInAccessList(addr common.Address) bool {
+	return sd.accessListAddrs[addr]
+}
+
+// SlotInAccessList returns whether addr and its slot are warm
+func (sd *StateDB) SlotInAccessList(addr common.Address, slot hash.Hash256) (addrOk bool, slotOk bool) {
+	addrOk = sd.accessListAddrs[addr]
+	slotOk = sd.accessListSlots[storageKey{addr: addr, key: slot}]
+	return addrOk, slotOk
+}
+
+// AddAddressToAccessList marks addr as warm for the remainder of the call
+func (sd *StateDB) AddAddressToAccessList(addr common.Address) {
+	if sd.accessListAddrs[addr] {
+		return
+	}
+	sd.journal.append(accessListAddAddressChange{addr: addr})
+	sd.accessListAddrs[addr] = true
+}
+
+// AddSlotToAccessList marks addr and slot as warm for the remainder of the call
+func (sd *StateDB) AddSlotToAccessList(addr common.Address, slot hash.Hash256) {
+	addrAdded := false
+	if !sd.accessListAddrs[addr] {
+		sd.journal.append(accessListAddAddressChange{addr: addr})
+		sd.accessListAddrs[addr] = true
+		addrAdded = true
+	}
+	key := storageKey{addr: addr, key: slot}
+	if sd.accessListSlots[key] {
+		return
+	}
+	sd.journal.append(accessListAddSlotChange{addr: addr, slot: slot, addrAdded: addrAdded})
+	sd.accessListSlots[key] = true
+}
+
+// Prepare resets the warm address/slot set for a new call and pre-warms
+// the sender, coinbase, destination, active precompiles and the
+// transaction's declared AccessList, per EIP-2929/EIP-3651.
+func (sd *StateDB) Prepare(sender, coinbase common.Address, dest *common.Address, precompiles []common.Address, list AccessList) {
+	sd.accessListAddrs = map[common.Address]bool{}
+	sd.accessListSlots = map[storageKey]bool{}
+
+	sd.AddAddressToAccessList(sender)
+	sd.AddAddressToAccessList(coinbase)
+	if dest != nil {
+		sd.AddAddressToAccessList(*dest)
+	}
+	for _, addr := range precompiles {
+		sd.AddAddressToAccessList(addr)
+	}
+	for _, el := range list {
+		sd.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			sd.AddSlotToAccessList(el.Address, key)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package solidity
+
+import (
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+)
+
+// journalEntry is a modification to a StateDB overlay that can be undone
+// on demand by RevertToSnapshot.
+type journalEntry interface {
+	revert(sd *StateDB)
+}
+
+// journal is an ordered list of in-memory state modifications applied to a
+// StateDB since it was created. It lets RevertToSnapshot undo the effect of
+// a nested EVM call by walking entries backwards instead of forking the
+// whole data.Context, and lets Finalise flush only the addresses that are
+// still dirty once the outermost call returns.
+type journal struct {
+	entries []journalEntry
+}
+
+func newJournal() *journal {
+	return &journal{}
+}
+
+func (j *journal) append(entry journalEntry) {
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) length() int {
+	return len(j.entries)
+}
+
+// revert undoes every entry recorded since snapshot n, in reverse order.
+func (j *journal) revert(sd *StateDB, n int) {
+	for i := len(j.entries) - 1; i >= n; i-- {
+		j.entries[i].revert(sd)
+	}
+	j.entries = j.entries[:n]
+}
+
+type balanceChange struct {
+	addr common.Address
+	prev *amount.Amount
+	had  bool
+}
+
+func (c balanceChange) revert(sd *StateDB) {
+	if c.had {
+		sd.balanceOverlay[c.addr] = c.prev
+	} else {
+		delete(sd.balanceOverlay, c.addr)
+	}
+}
+
+type seqChange struct {
+	addr common.Address
+	prev uint64
+	had  bool
+}
+
+func (c seqChange) revert(sd *StateDB) {
+	if c.had {
+		sd.seqOverlay[c.addr] = c.prev
+	} else {
+		delete(sd.seqOverlay, c.addr)
+	}
+}
+
+type storageKey struct {
+	addr common.Address
+	key  hash.Hash256
+}
+
+type storageChange struct {
+	key  storageKey
+	prev hash.Hash256
+	had  bool
+}
+
+func (c storageChange) revert(sd *StateDB) {
+	if c.had {
+		sd.storageOverlay[c.key] = c.prev
+	} else {
+		delete(sd.storageOverlay, c.key)
+	}
+}
+
+type codeChange struct {
+	addr     common.Address
+	prevCode []byte
+	prevHash hash.Hash256
+	prevSize int
+	had      bool
+}
+
+func (c codeChange) revert(sd *StateDB) {
+	if c.had {
+		sd.codeOverlay[c.addr] = &codeOverlayEntry{
+			code: c.prevCode,
+			hash: c.prevHash,
+			size: c.prevSize,
+		}
+	} else {
+		delete(sd.codeOverlay, c.addr)
+	}
+}
+
+type suicideChange struct {
+	addr common.Address
+	prev bool
+	had  bool
+}
+
+func (c suicideChange) revert(sd *StateDB) {
+	if c.had {
+		sd.suicideOverlay[c.addr] = c.prev
+	} else {
+		delete(sd.suicideOverlay, c.addr)
+	}
+}
+
+// createChange records that CreateAccount staged a new account in the
+// overlay. The account is only materialized in data.Context by Finalise, so
+// reverting it just drops it from the overlay, the same as any other
+// pending mutation.
+type createChange struct {
+	addr common.Address
+	prev *createOverlayEntry
+	had  bool
+}
+
+func (c createChange) revert(sd *StateDB) {
+	if c.had {
+		sd.createOverlay[c.addr] = c.prev
+	} else {
+		delete(sd.createOverlay, c.addr)
+	}
+}
+
+type logChange struct {
+	index int
+}
+
+func (c logChange) revert(sd *StateDB) {
+	sd.logs = sd.logs[:c.index]
+}
+
+type accessListAddAddressChange struct {
+	addr common.Address
+}
+
+func (c accessListAddAddressChange) revert(sd *StateDB) {
+	delete(sd.accessListAddrs, c.addr)
+}
+
+type accessListAddSlotChange struct {
+	addr      common.Address
+	slot      hash.Hash256
+	addrAdded bool
+}
+
+func (c accessListAddSlotChange) revert(sd *StateDB) {
+	delete(sd.accessListSlots, storageKey{addr: c.addr, key: c.slot})
+	if c.addrAdded {
+		delete(sd.accessListAddrs, c.addr)
+	}
+}
+
+type touchChange struct {
+	addr     common.Address
+	wasDirty bool
+}
+
+func (c touchChange) revert(sd *StateDB) {
+	if !c.wasDirty {
+		delete(sd.dirtyAddrs, c.addr)
+	}
+}
@@ -0,0 +1,73 @@
+package solidity
+
+import (
+	"math/big"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/core/amount"
+)
+
+// Precompile is implemented by native Go functionality that a deployed
+// contract can invoke as if it were EVM bytecode living at a fixed address,
+// the same way the EVM treats addresses 0x01-0x09 in upstream clients.
+type Precompile interface {
+	RequiredGas(input []byte) uint64
+	Run(ctx PrecompileContext, input []byte) ([]byte, error)
+}
+
+// PrecompileContext exposes the pieces of the active call a Precompile
+// needs without handing it the whole vm.EVM.
+type PrecompileContext interface {
+	StateDB() *StateDB
+	Caller() common.Address
+	Value() *amount.Amount
+	BlockNumber() *big.Int
+}
+
+// PrecompileRegistry maps addresses to the Precompile that should run
+// instead of EVM bytecode when a call targets them. It lets the Fleta chain
+// expose native Go functionality (staking, formulation, bridge operations)
+// to Solidity contracts without hardcoding addresses inside vm.
+type PrecompileRegistry struct {
+	precompiles map[common.Address]Precompile
+}
+
+// NewPrecompileRegistry returns an empty PrecompileRegistry.
+func NewPrecompileRegistry() *PrecompileRegistry {
+	return &PrecompileRegistry{
+		precompiles: map[common.Address]Precompile{},
+	}
+}
+
+// Register adds or replaces the Precompile served at addr.
+func (r *PrecompileRegistry) Register(addr common.Address, p Precompile) {
+	r.precompiles[addr] = p
+}
+
+// Unregister removes the Precompile served at addr, if any.
+func (r *PrecompileRegistry) Unregister(addr common.Address) {
+	delete(r.precompiles, addr)
+}
+
+// Get returns the Precompile registered at addr, if any, so vm.EVM can
+// dispatch to it before falling back to code execution.
+func (r *PrecompileRegistry) Get(addr common.Address) (Precompile, bool) {
+	p, has := r.precompiles[addr]
+	return p, has
+}
+
+// Addresses returns the addresses currently registered in r, so callers can
+// pre-warm them via StateDB.Prepare before the EIP-2929/2930 access list
+// would otherwise charge them at the cold gas cost on first access.
+func (r *PrecompileRegistry) Addresses() []common.Address {
+	addrs := make([]common.Address, 0, len(r.precompiles))
+	for addr := range r.precompiles {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// DefaultPrecompiles is the registry shared by CreateContract and
+// CallContract so that a single set of native extensions is available to
+// every contract on the chain.
+var DefaultPrecompiles = NewPrecompileRegistry()
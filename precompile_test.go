@@ -0,0 +1,52 @@
+package solidity
+
+import (
+	"testing"
+
+	"github.com/fletaio/common"
+)
+
+// stubPrecompile is a minimal Precompile used only to exercise
+// PrecompileRegistry's bookkeeping; it is never actually Run here.
+type stubPrecompile struct{}
+
+func (stubPrecompile) RequiredGas(input []byte) uint64 { return 0 }
+
+func (stubPrecompile) Run(ctx PrecompileContext, input []byte) ([]byte, error) { return nil, nil }
+
+func TestPrecompileRegistryRegisterGetUnregister(t *testing.T) {
+	r := NewPrecompileRegistry()
+	addr := common.Address{9}
+
+	if _, ok := r.Get(addr); ok {
+		t.Fatalf("Get should report not-found before Register")
+	}
+
+	p := stubPrecompile{}
+	r.Register(addr, p)
+	if got, ok := r.Get(addr); !ok || got != p {
+		t.Fatalf("Get = (%v, %v), want (%v, true)", got, ok, p)
+	}
+
+	r.Unregister(addr)
+	if _, ok := r.Get(addr); ok {
+		t.Fatalf("Get should report not-found after Unregister")
+	}
+}
+
+func TestPrecompileRegistryAddresses(t *testing.T) {
+	r := NewPrecompileRegistry()
+	a1 := common.Address{1}
+	a2 := common.Address{2}
+	r.Register(a1, stubPrecompile{})
+	r.Register(a2, stubPrecompile{})
+
+	want := map[common.Address]bool{a1: true, a2: true}
+	got := map[common.Address]bool{}
+	for _, addr := range r.Addresses() {
+		got[addr] = true
+	}
+	if len(got) != len(want) || !got[a1] || !got[a2] {
+		t.Fatalf("Addresses() = %v, want exactly %v", r.Addresses(), want)
+	}
+}
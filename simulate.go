@@ -0,0 +1,155 @@
+package solidity
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/solidity/vm"
+)
+
+// CallMsg describes a read-only call to Simulate or EstimateGas.
+type CallMsg struct {
+	From  common.Address
+	To    *common.Address
+	Value *amount.Amount
+	Input []byte
+	// GasLimit bounds the call via vm.Context.GasLimit; zero means
+	// unbounded, matching CreateContract/CallContract today.
+	GasLimit uint64
+
+	// Trace controls tracing for this call. Nil (or Enabled false) leaves
+	// Err unwrapped and emits no solidity.Trace event; when enabled, a nil
+	// Trace.Tracer falls back to whatever SetDefaultTracer last installed.
+	// SimulateResult.StructLogs is filled either way.
+	Trace *TraceOptions
+}
+
+// SimulateResult is the outcome of a Simulate call.
+type SimulateResult struct {
+	ReturnData []byte
+	Logs       []*vm.Log
+	Err        error
+	StructLogs []vm.StructLog
+}
+
+// Simulate runs msg against a snapshot of ctx and always reverts the
+// snapshot before returning, so it never mutates the committed chain
+// state. It is the foundation for exposing eth_call/eth_estimateGas-style
+// RPCs on the Fleta node.
+func Simulate(ctx *data.Context, coord *common.Coordinate, msg CallMsg) (result SimulateResult, rerr error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if err, is := e.(error); is {
+				rerr = err
+			} else {
+				rerr = ErrVirtualMachinePanic
+			}
+		}
+	}()
+
+	sn := ctx.Snapshot()
+	defer ctx.Revert(sn)
+
+	statedb := NewStateDB(ctx, coord)
+	logconfig := &vm.LogConfig{
+		DisableMemory: false,
+		DisableStack:  false,
+		Debug:         true,
+	}
+	tracer := vm.NewStructLogger(logconfig)
+	vmCfg := vm.Config{
+		Tracer:      tracer,
+		Debug:       true,
+		Precompiles: DefaultPrecompiles,
+	}
+	vctx := vm.Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     func(uint64) hash.Hash256 { return hash.Hash256{} },
+		Origin:      msg.From,
+		BlockNumber: new(big.Int).SetUint64(100),
+		Time:        big.NewInt(time.Now().Unix()),
+		Difficulty:  new(big.Int),
+		GasLimit:    msg.GasLimit,
+	}
+
+	value := msg.Value
+	if value == nil {
+		value = amount.NewCoinAmount(0, 0)
+	}
+
+	statedb.Prepare(msg.From, common.Address{}, msg.To, DefaultPrecompiles.Addresses(), nil)
+	evm := vm.NewEVM(vctx, statedb, vmCfg)
+
+	var (
+		ret []byte
+		err error
+	)
+	if msg.To == nil {
+		contAddr := common.NewAddress(coord, 0)
+		ret, err = evm.Create(vm.AccountRef(msg.From), contAddr, "", msg.Input, value)
+	} else {
+		ret, err = evm.Call(vm.AccountRef(msg.From), *msg.To, msg.Input, value)
+	}
+
+	err = traceExecution(ctx, coord, msg.Trace, tracer, err)
+
+	return SimulateResult{
+		ReturnData: ret,
+		Logs:       statedb.logs,
+		Err:        err,
+		StructLogs: tracer.StructLogs(),
+	}, nil
+}
+
+// defaultGasFloor and defaultGasCeiling bound the binary search performed
+// by EstimateGas when the caller does not know a reasonable starting range.
+const (
+	defaultGasFloor   uint64 = 21000
+	defaultGasCeiling uint64 = 8000000
+)
+
+// EstimateGas binary-searches gas between a floor and ceiling, re-running
+// Simulate at each step, and returns the smallest gas that yields a
+// non-reverting execution. Like Simulate, it never mutates chain state.
+func EstimateGas(ctx *data.Context, coord *common.Coordinate, msg CallMsg) (uint64, error) {
+	lo := defaultGasFloor
+	hi := defaultGasCeiling
+	if msg.GasLimit > 0 {
+		hi = msg.GasLimit
+	}
+
+	runs := func(gas uint64) (bool, error) {
+		trial := msg
+		trial.GasLimit = gas
+		result, err := Simulate(ctx, coord, trial)
+		if err != nil {
+			return false, err
+		}
+		return result.Err == nil, nil
+	}
+
+	if ok, err := runs(hi); err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, ErrOutOfGas
+	}
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := runs(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return hi, nil
+}
@@ -0,0 +1,30 @@
+package solidity
+
+import (
+	"testing"
+
+	"github.com/fletaio/common"
+)
+
+// Exercising Simulate/EstimateGas's commit-then-revert and binary-search
+// behavior end to end needs a live *data.Context backed by a real
+// data.Loader and a running vm.EVM executing real bytecode — this package
+// has nothing to construct either of those with standalone, the same
+// constraint newTestStateDB documents in statedb_test.go for StateDB. What IS
+// verifiable at this level is the panic-recovery contract Simulate relies
+// on: a Context access that panics (here, any call on a nil *data.Context)
+// must surface as an error return, never crash the caller.
+func TestSimulateRecoversPanicAsError(t *testing.T) {
+	msg := CallMsg{From: common.Address{1}}
+
+	result, err := Simulate(nil, nil, msg)
+	if err == nil {
+		t.Fatalf("Simulate should have recovered the panic into its error return, got result=%+v, err=nil", result)
+	}
+}
+
+func TestEstimateGasBounds(t *testing.T) {
+	if defaultGasFloor >= defaultGasCeiling {
+		t.Fatalf("defaultGasFloor (%d) must be below defaultGasCeiling (%d)", defaultGasFloor, defaultGasCeiling)
+	}
+}
@@ -25,52 +25,119 @@ func init() {
 	KeywordMap[KeywordSuicide] = true
 }
 
-// StateDB is an EVM database for full state querying.
+// codeOverlayEntry is the in-memory view of an account's code while it is
+// dirty, kept together so a single codeChange entry can restore all three
+// derived values (code, hash, size) at once.
+type codeOverlayEntry struct {
+	code []byte
+	hash hash.Hash256
+	size int
+}
+
+// createOverlayEntry is the in-memory record of an account CreateAccount
+// staged but that has not been materialized in data.Context yet.
+type createOverlayEntry struct {
+	name string
+}
+
+// StateDB is an EVM database for full state querying. Every mutation is
+// recorded in an in-memory overlay guarded by a journal, so intra-call
+// reverts (EVM CALL/CREATE failures) never touch data.Context; only
+// Finalise, called once the outermost call succeeds, writes the surviving
+// values through to the underlying context.
 type StateDB struct {
 	Context *data.Context
 	Coord   *common.Coordinate
+
+	journal    *journal
+	dirtyAddrs map[common.Address]bool
+
+	balanceOverlay map[common.Address]*amount.Amount
+	seqOverlay     map[common.Address]uint64
+	storageOverlay map[storageKey]hash.Hash256
+	codeOverlay    map[common.Address]*codeOverlayEntry
+	suicideOverlay map[common.Address]bool
+	createOverlay  map[common.Address]*createOverlayEntry
+
+	accessListAddrs map[common.Address]bool
+	accessListSlots map[storageKey]bool
+
+	logs []*vm.Log
 }
 
-// CreateAccount creates the sub account of the address to the context inside of EVM
-func (sd *StateDB) CreateAccount(addr common.Address, name string) {
-	//log.Println("CreateAccount", addr)
-	a, err := sd.Context.Accounter().NewByTypeName("solidity.ContractAccount")
-	if err != nil {
-		panic(err)
+// NewStateDB creates a StateDB wrapping ctx with an empty overlay.
+func NewStateDB(ctx *data.Context, coord *common.Coordinate) *StateDB {
+	return &StateDB{
+		Context: ctx,
+		Coord:   coord,
+
+		journal:    newJournal(),
+		dirtyAddrs: map[common.Address]bool{},
+
+		balanceOverlay: map[common.Address]*amount.Amount{},
+		seqOverlay:     map[common.Address]uint64{},
+		storageOverlay: map[storageKey]hash.Hash256{},
+		codeOverlay:    map[common.Address]*codeOverlayEntry{},
+		suicideOverlay: map[common.Address]bool{},
+		createOverlay:  map[common.Address]*createOverlayEntry{},
+
+		accessListAddrs: map[common.Address]bool{},
+		accessListSlots: map[storageKey]bool{},
 	}
-	acc := a.(*ContractAccount)
-	acc.Address_ = addr
-	acc.Name_ = name
-	if err := sd.Context.CreateAccount(acc); err != nil {
-		panic(err)
+}
+
+func (sd *StateDB) markDirty(addr common.Address) {
+	if !sd.dirtyAddrs[addr] {
+		sd.journal.append(touchChange{addr: addr, wasDirty: false})
+		sd.dirtyAddrs[addr] = true
 	}
 }
 
+// CreateAccount stages the sub account of the address so it is created
+// against data.Context only once Finalise runs; an EVM call that creates an
+// account and then reverts (e.g. a failed nested CREATE/CALL caught by the
+// caller) must never leave the account behind.
+func (sd *StateDB) CreateAccount(addr common.Address, name string) {
+	//log.Println("CreateAccount", addr)
+	prev, had := sd.createOverlay[addr]
+	sd.journal.append(createChange{addr: addr, prev: prev, had: had})
+	sd.createOverlay[addr] = &createOverlayEntry{name: name}
+	sd.markDirty(addr)
+}
+
 // SubBalance reduce the balance from the account of the address
 func (sd *StateDB) SubBalance(addr common.Address, b *amount.Amount) {
 	//log.Println("SubBalance", addr, b)
-	acc, err := sd.Context.Account(addr)
-	if err != nil {
-		panic(err)
-	}
-	if err := acc.SubBalance(b); err != nil {
-		panic(err)
+	if b.IsZero() {
+		return
 	}
+	prev, had := sd.balanceOverlay[addr]
+	sd.journal.append(balanceChange{addr: addr, prev: prev, had: had})
+	sd.balanceOverlay[addr] = sd.GetBalance(addr).Sub(b)
+	sd.markDirty(addr)
 }
 
 // AddBalance add the balance to the account of the address
 func (sd *StateDB) AddBalance(addr common.Address, b *amount.Amount) {
 	//log.Println("AddBalance", addr, b)
-	acc, err := sd.Context.Account(addr)
-	if err != nil {
-		panic(err)
+	if b.IsZero() {
+		return
 	}
-	acc.AddBalance(b)
+	prev, had := sd.balanceOverlay[addr]
+	sd.journal.append(balanceChange{addr: addr, prev: prev, had: had})
+	sd.balanceOverlay[addr] = sd.GetBalance(addr).Add(b)
+	sd.markDirty(addr)
 }
 
 // GetBalance returns the balance from the account of the address
 func (sd *StateDB) GetBalance(addr common.Address) *amount.Amount {
 	//log.Println("GetBalance", addr)
+	if b, ok := sd.balanceOverlay[addr]; ok {
+		return b
+	}
+	if _, ok := sd.createOverlay[addr]; ok {
+		return amount.NewCoinAmount(0, 0)
+	}
 	acc, err := sd.Context.Account(addr)
 	if err != nil {
 		panic(err)
@@ -81,41 +148,63 @@ func (sd *StateDB) GetBalance(addr common.Address) *amount.Amount {
 // GetSeq returns the sequence of the address
 func (sd *StateDB) GetSeq(addr common.Address) uint64 {
 	//log.Println("GetSeq", addr)
+	if s, ok := sd.seqOverlay[addr]; ok {
+		return s
+	}
 	return sd.Context.Seq(addr)
 }
 
 // AddSeq adds the sequence of the address
 func (sd *StateDB) AddSeq(addr common.Address) {
 	//log.Println("AddSeq", addr)
-	sd.Context.AddSeq(addr)
+	prev, had := sd.seqOverlay[addr]
+	sd.journal.append(seqChange{addr: addr, prev: prev, had: had})
+	sd.seqOverlay[addr] = sd.GetSeq(addr) + 1
+	sd.markDirty(addr)
 }
 
 // GetCodeHash returns the code hash of the address
 func (sd *StateDB) GetCodeHash(addr common.Address) hash.Hash256 {
 	//log.Println("GetCodeHash", addr)
+	if c, ok := sd.codeOverlay[addr]; ok {
+		return c.hash
+	}
 	return sd.GetState(addr, KeywordCodeHash)
 }
 
 // GetCode returns the code of the address
 func (sd *StateDB) GetCode(addr common.Address) []byte {
 	//log.Println("GetCode", addr)
+	if c, ok := sd.codeOverlay[addr]; ok {
+		return c.code
+	}
 	return sd.Context.AccountData(addr, KeywordCode[:])
 }
 
 // SetCode updates the code to the address
 func (sd *StateDB) SetCode(addr common.Address, code []byte) {
 	//log.Println("SetCode", addr, code)
-	sd.Context.SetAccountData(addr, KeywordCode[:], code)
+	prev, had := sd.codeOverlay[addr]
+	if had {
+		sd.journal.append(codeChange{addr: addr, prevCode: prev.code, prevHash: prev.hash, prevSize: prev.size, had: true})
+	} else {
+		sd.journal.append(codeChange{addr: addr, had: false})
+	}
 	h := hash.Hash(code)
-	sd.Context.SetAccountData(addr, KeywordCodeHash[:], h[:])
-	bs := make([]byte, 4)
-	binary.LittleEndian.PutUint32(bs, uint32(len(code)))
-	sd.Context.SetAccountData(addr, KeywordCodeSize[:], bs)
+	sd.codeOverlay[addr] = &codeOverlayEntry{
+		code: code,
+		hash: h,
+		size: len(code),
+	}
+	sd.markDirty(addr)
 }
 
 // GetCodeSize returns the code size of the address
 func (sd *StateDB) GetCodeSize(addr common.Address) int {
 	//log.Println("GetCodeSize", addr)
+	if c, ok := sd.codeOverlay[addr]; ok {
+		return c.size
+	}
 	bs := sd.Context.AccountData(addr, KeywordCodeSize[:])
 	var Len int
 	if len(bs) == 4 {
@@ -127,6 +216,10 @@ func (sd *StateDB) GetCodeSize(addr common.Address) int {
 // GetState returns value by the hash of the address
 func (sd *StateDB) GetState(addr common.Address, h hash.Hash256) hash.Hash256 {
 	//log.Println("GetState", addr, h)
+	key := storageKey{addr: addr, key: h}
+	if v, ok := sd.storageOverlay[key]; ok {
+		return v
+	}
 	var ret hash.Hash256
 	bs := sd.Context.AccountData(addr, h[:])
 	if len(bs) > 0 {
@@ -141,19 +234,29 @@ func (sd *StateDB) SetState(addr common.Address, h hash.Hash256, v hash.Hash256)
 	if KeywordMap[h] {
 		panic("reserved keyword")
 	}
-	sd.Context.SetAccountData(addr, h[:], v[:])
+	key := storageKey{addr: addr, key: h}
+	prev, had := sd.storageOverlay[key]
+	sd.journal.append(storageChange{key: key, prev: prev, had: had})
+	sd.storageOverlay[key] = v
+	sd.markDirty(addr)
 }
 
 // Suicide make the address to dead state
 func (sd *StateDB) Suicide(addr common.Address) bool {
 	//log.Println("Suicide", addr)
-	sd.Context.SetAccountData(addr, KeywordSuicide[:], []byte{1})
+	prev, had := sd.suicideOverlay[addr]
+	sd.journal.append(suicideChange{addr: addr, prev: prev, had: had})
+	sd.suicideOverlay[addr] = true
+	sd.markDirty(addr)
 	return true
 }
 
 // HasSuicided checks the dead state of the address
 func (sd *StateDB) HasSuicided(addr common.Address) bool {
 	//log.Println("HasSuicided", addr)
+	if v, ok := sd.suicideOverlay[addr]; ok {
+		return v
+	}
 	bs := sd.Context.AccountData(addr, KeywordSuicide[:])
 	return len(bs) > 0 && bs[0] == 1
 }
@@ -161,6 +264,9 @@ func (sd *StateDB) HasSuicided(addr common.Address) bool {
 // Exist checks that the account of the address is exist or not
 func (sd *StateDB) Exist(addr common.Address) bool {
 	//log.Println("Exist", addr)
+	if _, ok := sd.createOverlay[addr]; ok {
+		return true
+	}
 	if exist, err := sd.Context.IsExistAccount(addr); err != nil {
 		panic(err)
 	} else {
@@ -171,43 +277,104 @@ func (sd *StateDB) Exist(addr common.Address) bool {
 // Empty checks that seq == 0, balance == 0, code size == 0
 func (sd *StateDB) Empty(addr common.Address) bool {
 	//log.Println("Empty", addr)
-	acc, err := sd.Context.Account(addr)
-	if err != nil {
-		panic(err)
-	}
-	return sd.Context.Seq(addr) == 0 && acc.Balance().IsZero() && sd.GetCodeSize(addr) == 0
+	return sd.GetSeq(addr) == 0 && sd.GetBalance(addr).IsZero() && sd.GetCodeSize(addr) == 0
 }
 
-// RevertToSnapshot removes snapshots after the snapshot number
+// RevertToSnapshot undoes every overlay entry recorded since snapshot n
 func (sd *StateDB) RevertToSnapshot(n int) {
 	//log.Println("RevertToSnapshot", n)
-	sd.Context.Revert(n)
+	sd.journal.revert(sd, n)
 }
 
-// CommitSnapshot apply snapshots to the top after the snapshot number
+// CommitSnapshot is a no-op: entries before n already live in the overlay
+// and are only written through to data.Context once by Finalise
 func (sd *StateDB) CommitSnapshot(n int) {
 	//log.Println("CommitSnapshot", n)
-	sd.Context.Commit(n)
 }
 
-// Snapshot push a snapshot and returns the snapshot number of it
+// Snapshot returns the current length of the journal
 func (sd *StateDB) Snapshot() int {
-	n := sd.Context.Snapshot()
+	n := sd.journal.length()
 	//log.Println("Snapshot", n)
 	return n
 }
 
-// AddLog not implemented yet
+// AddLog buffers the log so it can be dropped on revert; Finalise emits
+// whatever survives as solidity.Log events
 func (sd *StateDB) AddLog(l *vm.Log) {
-	e, err := sd.Context.Eventer().NewByTypeName("solidity.Log")
-	if err != nil {
-		panic(err)
+	sd.journal.append(logChange{index: len(sd.logs)})
+	sd.logs = append(sd.logs, l)
+	sd.markDirty(l.Address)
+}
+
+// Finalise writes every overlay value for an address touched since the
+// StateDB was created through to data.Context, and emits the surviving
+// logs. It must be called exactly once, after the outermost EVM call has
+// returned successfully and before the transaction executor commits its
+// own data.Context snapshot.
+func (sd *StateDB) Finalise() {
+	for addr := range sd.dirtyAddrs {
+		if c, ok := sd.createOverlay[addr]; ok {
+			a, err := sd.Context.Accounter().NewByTypeName("solidity.ContractAccount")
+			if err != nil {
+				panic(err)
+			}
+			acc := a.(*ContractAccount)
+			acc.Address_ = addr
+			acc.Name_ = c.name
+			if err := sd.Context.CreateAccount(acc); err != nil {
+				panic(err)
+			}
+		}
+		if final, ok := sd.balanceOverlay[addr]; ok {
+			acc, err := sd.Context.Account(addr)
+			if err != nil {
+				panic(err)
+			}
+			cur := acc.Balance()
+			if cur.Less(final) {
+				acc.AddBalance(final.Sub(cur))
+			} else if final.Less(cur) {
+				if err := acc.SubBalance(cur.Sub(final)); err != nil {
+					panic(err)
+				}
+			}
+		}
+		if final, ok := sd.seqOverlay[addr]; ok {
+			for cur := sd.Context.Seq(addr); cur < final; cur++ {
+				sd.Context.AddSeq(addr)
+			}
+		}
+		if c, ok := sd.codeOverlay[addr]; ok {
+			sd.Context.SetAccountData(addr, KeywordCode[:], c.code)
+			sd.Context.SetAccountData(addr, KeywordCodeHash[:], c.hash[:])
+			bs := make([]byte, 4)
+			binary.LittleEndian.PutUint32(bs, uint32(c.size))
+			sd.Context.SetAccountData(addr, KeywordCodeSize[:], bs)
+		}
+		if dead, ok := sd.suicideOverlay[addr]; ok && dead {
+			sd.Context.SetAccountData(addr, KeywordSuicide[:], []byte{1})
+		}
+	}
+	for key, v := range sd.storageOverlay {
+		sd.Context.SetAccountData(key.addr, key.key[:], v[:])
 	}
-	ev := e.(*LogEvent)
-	ev.Coord_ = sd.Coord
-	ev.Address = l.Address
-	ev.Topics = l.Topics
-	ev.Data = l.Data
-	ev.Removed = l.Removed
-	sd.Context.EmitEvent(e)
+
+	for _, l := range sd.logs {
+		e, err := sd.Context.Eventer().NewByTypeName("solidity.Log")
+		if err != nil {
+			panic(err)
+		}
+		ev := e.(*LogEvent)
+		ev.Coord_ = sd.Coord
+		ev.Address = l.Address
+		ev.Topics = l.Topics
+		ev.Data = l.Data
+		ev.Removed = l.Removed
+		sd.Context.EmitEvent(e)
+	}
+
+	sd.dirtyAddrs = map[common.Address]bool{}
+	sd.logs = nil
+	sd.journal = newJournal()
 }
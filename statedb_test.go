@@ -0,0 +1,180 @@
+package solidity
+
+import (
+	"testing"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+)
+
+// newTestStateDB returns a StateDB with a nil Context, which is safe for
+// these cases: the access list cases never read account state, and every
+// other case reads only accounts it has staged with CreateAccount or written
+// first. GetBalance/GetCode/GetState/Exist all consult their overlay (and
+// CreateAccount's overlay for GetBalance/Exist) before ever falling through
+// to sd.Context. Finalise's data.Context writes are out of scope here since
+// this package has no in-memory data.Context to construct one against.
+func newTestStateDB() *StateDB {
+	return NewStateDB(nil, nil)
+}
+
+func amountEqual(a, b *amount.Amount) bool {
+	return !a.Less(b) && !b.Less(a)
+}
+
+func TestStateDBSnapshotRevert(t *testing.T) {
+	addr := common.Address{1}
+	key := hash.Hash([]byte("slot"))
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, sd *StateDB)
+	}{
+		{
+			name: "balance reverts to the pre-snapshot value",
+			run: func(t *testing.T, sd *StateDB) {
+				sd.CreateAccount(addr, "test")
+				sd.AddBalance(addr, amount.NewCoinAmount(0, 100))
+
+				snap := sd.Snapshot()
+				sd.AddBalance(addr, amount.NewCoinAmount(0, 50))
+				if got, want := sd.GetBalance(addr), amount.NewCoinAmount(0, 150); !amountEqual(got, want) {
+					t.Fatalf("balance before revert = %v, want %v", got, want)
+				}
+
+				sd.RevertToSnapshot(snap)
+				if got, want := sd.GetBalance(addr), amount.NewCoinAmount(0, 100); !amountEqual(got, want) {
+					t.Fatalf("balance after revert = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "storage reverts to the pre-snapshot value",
+			run: func(t *testing.T, sd *StateDB) {
+				sd.CreateAccount(addr, "test")
+				first := hash.Hash([]byte("first"))
+				sd.SetState(addr, key, first)
+
+				snap := sd.Snapshot()
+				second := hash.Hash([]byte("second"))
+				sd.SetState(addr, key, second)
+				if got := sd.GetState(addr, key); got != second {
+					t.Fatalf("state before revert = %x, want %x", got, second)
+				}
+
+				sd.RevertToSnapshot(snap)
+				if got := sd.GetState(addr, key); got != first {
+					t.Fatalf("state after revert = %x, want %x", got, first)
+				}
+			},
+		},
+		{
+			name: "code reverts to the pre-snapshot value",
+			run: func(t *testing.T, sd *StateDB) {
+				sd.CreateAccount(addr, "test")
+				sd.SetCode(addr, []byte{0x60, 0x01})
+
+				snap := sd.Snapshot()
+				sd.SetCode(addr, []byte{0x60, 0x02})
+				if got := sd.GetCode(addr); string(got) != string([]byte{0x60, 0x02}) {
+					t.Fatalf("code before revert = %x, want 6002", got)
+				}
+
+				sd.RevertToSnapshot(snap)
+				if got := sd.GetCode(addr); string(got) != string([]byte{0x60, 0x01}) {
+					t.Fatalf("code after revert = %x, want 6001", got)
+				}
+			},
+		},
+		{
+			name: "a reverted CreateAccount drops the staged account entirely",
+			run: func(t *testing.T, sd *StateDB) {
+				snap := sd.Snapshot()
+				sd.CreateAccount(addr, "test")
+				if !sd.Exist(addr) {
+					t.Fatalf("account should exist while staged")
+				}
+
+				sd.RevertToSnapshot(snap)
+				if _, ok := sd.createOverlay[addr]; ok {
+					t.Fatalf("createOverlay still holds %v after revert", addr)
+				}
+				if sd.dirtyAddrs[addr] {
+					t.Fatalf("dirtyAddrs still marks %v after revert", addr)
+				}
+			},
+		},
+		{
+			name: "access list additions revert to the pre-snapshot warm set",
+			run: func(t *testing.T, sd *StateDB) {
+				other := common.Address{2}
+
+				sd.AddAddressToAccessList(addr)
+
+				snap := sd.Snapshot()
+				sd.AddSlotToAccessList(other, key)
+				if addrOk, slotOk := sd.SlotInAccessList(other, key); !addrOk || !slotOk {
+					t.Fatalf("other/key should be warm before revert, got addrOk=%v slotOk=%v", addrOk, slotOk)
+				}
+
+				sd.RevertToSnapshot(snap)
+				if sd.AddressInAccessList(other) {
+					t.Fatalf("other should be cold after revert")
+				}
+				if addrOk, slotOk := sd.SlotInAccessList(other, key); addrOk || slotOk {
+					t.Fatalf("other/key should be cold after revert, got addrOk=%v slotOk=%v", addrOk, slotOk)
+				}
+				if !sd.AddressInAccessList(addr) {
+					t.Fatalf("addr added before the snapshot should still be warm")
+				}
+			},
+		},
+		{
+			name: "a slot added on an already-warm address stays warm after revert",
+			run: func(t *testing.T, sd *StateDB) {
+				sd.AddAddressToAccessList(addr)
+
+				snap := sd.Snapshot()
+				sd.AddSlotToAccessList(addr, key)
+
+				sd.RevertToSnapshot(snap)
+				if !sd.AddressInAccessList(addr) {
+					t.Fatalf("addr should still be warm after revert, only the slot change should unwind")
+				}
+				if _, slotOk := sd.SlotInAccessList(addr, key); slotOk {
+					t.Fatalf("slot should be cold after revert")
+				}
+			},
+		},
+		{
+			name: "nested snapshots revert independently",
+			run: func(t *testing.T, sd *StateDB) {
+				sd.CreateAccount(addr, "test")
+				sd.AddBalance(addr, amount.NewCoinAmount(0, 10))
+
+				outer := sd.Snapshot()
+				sd.AddBalance(addr, amount.NewCoinAmount(0, 20))
+
+				inner := sd.Snapshot()
+				sd.AddBalance(addr, amount.NewCoinAmount(0, 30))
+
+				sd.RevertToSnapshot(inner)
+				if got, want := sd.GetBalance(addr), amount.NewCoinAmount(0, 30); !amountEqual(got, want) {
+					t.Fatalf("balance after inner revert = %v, want %v", got, want)
+				}
+
+				sd.RevertToSnapshot(outer)
+				if got, want := sd.GetBalance(addr), amount.NewCoinAmount(0, 10); !amountEqual(got, want) {
+					t.Fatalf("balance after outer revert = %v, want %v", got, want)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newTestStateDB())
+		})
+	}
+}
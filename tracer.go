@@ -0,0 +1,160 @@
+package solidity
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/solidity/vm"
+)
+
+// Tracer turns the opcode-level trace captured by a vm.StructLogger into a
+// caller-facing representation, mirroring debug_traceTransaction.
+type Tracer interface {
+	Trace(logs []vm.StructLog) (json.RawMessage, error)
+}
+
+// TraceOptions customizes how a single call is traced, overriding the
+// module-level default Tracer set by SetDefaultTracer. The zero value
+// leaves tracing off, so callers must opt in explicitly.
+type TraceOptions struct {
+	Tracer        Tracer
+	Enabled       bool
+	EmitOnSuccess bool
+}
+
+// TraceFlags is the wire-serializable subset of TraceOptions a transaction
+// can carry: a Tracer is Go-level behavior and cannot travel over the wire,
+// so CreateContract/CallContract only get to toggle Enabled/EmitOnSuccess.
+// The zero value (Enabled: false) keeps tracing off, matching the cost of a
+// transaction that never mentions tracing at all. In-process callers that
+// need a custom Tracer use Simulate's CallMsg.Trace directly.
+type TraceFlags struct {
+	Enabled       bool `json:"enabled"`
+	EmitOnSuccess bool `json:"emitOnSuccess"`
+}
+
+// Options returns the TraceOptions equivalent to f, deferring to the
+// module-level default Tracer.
+func (f TraceFlags) Options() *TraceOptions {
+	return &TraceOptions{Enabled: f.Enabled, EmitOnSuccess: f.EmitOnSuccess}
+}
+
+// encode packs f into a single byte for (de)serialization.
+func (f TraceFlags) encode() byte {
+	var b byte
+	if f.Enabled {
+		b |= 0x1
+	}
+	if f.EmitOnSuccess {
+		b |= 0x2
+	}
+	return b
+}
+
+// decodeTraceFlags unpacks a byte written by TraceFlags.encode.
+func decodeTraceFlags(b byte) TraceFlags {
+	return TraceFlags{
+		Enabled:       b&0x1 != 0,
+		EmitOnSuccess: b&0x2 != 0,
+	}
+}
+
+// StructLogEntry is the per-opcode shape emitted by JSONTracer.
+type StructLogEntry struct {
+	Pc      uint64 `json:"pc"`
+	Op      string `json:"op"`
+	Gas     uint64 `json:"gas"`
+	GasCost uint64 `json:"gasCost"`
+	Depth   int    `json:"depth"`
+	Stack   string `json:"stack,omitempty"`
+	Memory  string `json:"memory,omitempty"`
+	Storage string `json:"storage,omitempty"`
+}
+
+// JSONTracer is the default Tracer: it converts each vm.StructLog entry
+// into a StructLogEntry and marshals the result as a JSON array.
+type JSONTracer struct{}
+
+// Trace implements Tracer
+func (JSONTracer) Trace(logs []vm.StructLog) (json.RawMessage, error) {
+	entries := make([]StructLogEntry, 0, len(logs))
+	for _, l := range logs {
+		entries = append(entries, StructLogEntry{
+			Pc:      l.Pc,
+			Op:      fmt.Sprintf("%v", l.Op),
+			Gas:     l.Gas,
+			GasCost: l.GasCost,
+			Depth:   l.Depth,
+			Stack:   fmt.Sprintf("%v", l.Stack),
+			Memory:  fmt.Sprintf("%x", l.Memory),
+			Storage: fmt.Sprintf("%v", l.Storage),
+		})
+	}
+	return json.Marshal(entries)
+}
+
+var defaultTracer Tracer = JSONTracer{}
+
+// SetDefaultTracer overrides the Tracer used by CreateContract, CallContract
+// and Simulate whenever a call does not provide its own TraceOptions.Tracer.
+func SetDefaultTracer(t Tracer) {
+	defaultTracer = t
+}
+
+// TracedError wraps an execution error together with the trace collected up
+// to the point of failure, so operators can diagnose reverts without
+// re-running the transaction.
+type TracedError struct {
+	Err   error
+	Trace json.RawMessage
+}
+
+// Error implements error
+func (e *TracedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap lets errors.Is/errors.As see through to the underlying error
+func (e *TracedError) Unwrap() error {
+	return e.Err
+}
+
+// traceExecution is a no-op unless opts.Enabled; when it is, it resolves the
+// Tracer to use for the call (opts.Tracer, falling back to defaultTracer),
+// and on failure returns err wrapped in a TracedError carrying the trace; on
+// success it emits a solidity.Trace event when opts asks for it and
+// otherwise returns err unchanged.
+func traceExecution(ctx *data.Context, coord *common.Coordinate, opts *TraceOptions, sl *vm.StructLogger, err error) error {
+	if opts == nil || !opts.Enabled {
+		return err
+	}
+	tracer := defaultTracer
+	if opts.Tracer != nil {
+		tracer = opts.Tracer
+	}
+	if tracer == nil || sl == nil {
+		return err
+	}
+
+	trace, terr := tracer.Trace(sl.StructLogs())
+	if terr != nil {
+		return err
+	}
+
+	if err != nil {
+		return &TracedError{Err: err, Trace: trace}
+	}
+
+	if opts != nil && opts.EmitOnSuccess {
+		e, eerr := ctx.Eventer().NewByTypeName("solidity.Trace")
+		if eerr == nil {
+			ev := e.(*TraceEvent)
+			ev.Coord_ = coord
+			ev.Trace = trace
+			ctx.EmitEvent(e)
+		}
+	}
+	return nil
+}
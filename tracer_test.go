@@ -0,0 +1,44 @@
+package solidity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fletaio/solidity/vm"
+)
+
+func TestTraceFlagsEncodeDecodeRoundTrip(t *testing.T) {
+	cases := []TraceFlags{
+		{},
+		{Enabled: true},
+		{EmitOnSuccess: true},
+		{Enabled: true, EmitOnSuccess: true},
+	}
+	for _, want := range cases {
+		if got := decodeTraceFlags(want.encode()); got != want {
+			t.Fatalf("decodeTraceFlags(%+v.encode()) = %+v, want %+v", want, got, want)
+		}
+	}
+}
+
+func TestTraceExecutionWrapsErrorOnlyWhenEnabled(t *testing.T) {
+	sl := vm.NewStructLogger(&vm.LogConfig{})
+	origErr := errors.New("boom")
+
+	if got := traceExecution(nil, nil, nil, sl, origErr); got != origErr {
+		t.Fatalf("nil opts: got %v, want the original error unwrapped", got)
+	}
+
+	if got := traceExecution(nil, nil, &TraceOptions{Enabled: false}, sl, origErr); got != origErr {
+		t.Fatalf("Enabled=false: got %v, want the original error unwrapped", got)
+	}
+
+	got := traceExecution(nil, nil, &TraceOptions{Enabled: true}, sl, origErr)
+	traced, ok := got.(*TracedError)
+	if !ok {
+		t.Fatalf("Enabled=true: got %T, want *TracedError", got)
+	}
+	if traced.Err != origErr {
+		t.Fatalf("TracedError.Err = %v, want %v", traced.Err, origErr)
+	}
+}
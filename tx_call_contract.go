@@ -0,0 +1,392 @@
+package solidity
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/solidity/vm"
+)
+
+func init() {
+	data.RegisterTransaction("solidity.CallContract", func(t transaction.Type) transaction.Transaction {
+		return &CallContract{
+			Base: transaction.Base{
+				Type_: t,
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*CallContract)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+
+		if len(signers) > 1 {
+			return ErrInvalidSignerCount
+		}
+		if !allowedKeyMap[signers[0]] {
+			return ErrNotAllowed
+		}
+
+		fromAcc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+
+		if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		defer func() {
+			if e := recover(); e != nil {
+				if err, is := e.(error); is {
+					rerr = err
+				} else {
+					rerr = ErrVirtualMachinePanic
+				}
+			}
+		}()
+
+		tx := t.(*CallContract)
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		fromAcc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		if err := fromAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+
+		if is, err := ctx.IsExistAccount(tx.To); err != nil {
+			return nil, err
+		} else if !is {
+			return nil, ErrNotExistContract
+		}
+
+		statedb := NewStateDB(ctx, coord)
+		traceEnabled := tx.Trace.Enabled
+		logconfig := &vm.LogConfig{
+			DisableMemory: false,
+			DisableStack:  false,
+			Debug:         traceEnabled,
+		}
+		vmCfg := vm.Config{
+			Tracer:      vm.NewStructLogger(logconfig),
+			Debug:       traceEnabled,
+			Precompiles: DefaultPrecompiles,
+		}
+		vctx := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			GetHash:     func(uint64) hash.Hash256 { return hash.Hash256{} },
+			Origin:      tx.From(),
+			BlockNumber: new(big.Int).SetUint64(100),
+			Time:        big.NewInt(time.Now().Unix()),
+			Difficulty:  new(big.Int),
+		}
+		statedb.Prepare(tx.From(), common.Address{}, &tx.To, DefaultPrecompiles.Addresses(), tx.AccessList)
+
+		evm := vm.NewEVM(vctx, statedb, vmCfg)
+		ret, err = evm.Call(vm.AccountRef(tx.From()), tx.To, tx.Input, tx.Value)
+		if terr := traceExecution(ctx, coord, tx.Trace.Options(), vmCfg.Tracer.(*vm.StructLogger), err); terr != nil {
+			return nil, terr
+		}
+		statedb.Finalise()
+		ctx.Commit(sn)
+		return ret, nil
+	})
+}
+
+// CallContract is a solidity.CallContract
+// It is used to call the deployed contract
+type CallContract struct {
+	transaction.Base
+	Seq_  uint64
+	From_ common.Address
+	To    common.Address
+	Value *amount.Amount
+	Input []byte
+
+	// AccessList optionally pre-declares addresses and storage slots the
+	// call will touch so they are charged at the warm EIP-2929/2930 gas
+	// cost for the whole call instead of the cold cost on first access.
+	AccessList AccessList
+
+	// Trace toggles the opcode trace for this call; the Tracer implementation
+	// itself always comes from SetDefaultTracer, since a Tracer is Go-level
+	// behavior and cannot travel over the wire.
+	Trace TraceFlags
+}
+
+// IsUTXO returns false
+func (tx *CallContract) IsUTXO() bool {
+	return false
+}
+
+// From returns the caller of the transaction
+func (tx *CallContract) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *CallContract) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *CallContract) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// WriteTo is a serialization function
+func (tx *CallContract) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.To.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.Value.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteBytes(w, tx.Input); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, uint64(len(tx.AccessList))); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	for _, al := range tx.AccessList {
+		if n, err := al.Address.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+		if n, err := util.WriteUint64(w, uint64(len(al.StorageKeys))); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+		for _, key := range al.StorageKeys {
+			if n, err := w.Write(key[:]); err != nil {
+				return wrote, err
+			} else {
+				wrote += int64(n)
+			}
+		}
+	}
+	if n, err := w.Write([]byte{tx.Trace.encode()}); err != nil {
+		return wrote, err
+	} else {
+		wrote += int64(n)
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *CallContract) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.To.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	tx.Value = amount.NewCoinAmount(0, 0)
+	if n, err := tx.Value.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if bs, n, err := util.ReadBytes(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Input = bs
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.AccessList = make(AccessList, 0, v)
+		for i := uint64(0); i < v; i++ {
+			var al AccessTuple
+			if n, err := al.Address.ReadFrom(r); err != nil {
+				return read, err
+			} else {
+				read += n
+			}
+			if sv, n, err := util.ReadUint64(r); err != nil {
+				return read, err
+			} else {
+				read += n
+				al.StorageKeys = make([]hash.Hash256, 0, sv)
+				for j := uint64(0); j < sv; j++ {
+					var key hash.Hash256
+					if n, err := io.ReadFull(r, key[:]); err != nil {
+						return read, err
+					} else {
+						read += int64(n)
+					}
+					al.StorageKeys = append(al.StorageKeys, key)
+				}
+			}
+			tx.AccessList = append(tx.AccessList, al)
+		}
+	}
+	{
+		var buf [1]byte
+		if n, err := io.ReadFull(r, buf[:]); err != nil {
+			return read, err
+		} else {
+			read += int64(n)
+		}
+		tx.Trace = decodeTraceFlags(buf[0])
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *CallContract) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"to":`)
+	if bs, err := tx.To.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"value":`)
+	if bs, err := tx.Value.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"input":`)
+	if len(tx.Input) == 0 {
+		buffer.WriteString(`null`)
+	} else {
+		buffer.WriteString(`"`)
+		buffer.WriteString(hex.EncodeToString(tx.Input))
+		buffer.WriteString(`"`)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"accessList":`)
+	buffer.WriteString(`[`)
+	for i, al := range tx.AccessList {
+		if i > 0 {
+			buffer.WriteString(`,`)
+		}
+		buffer.WriteString(`{`)
+		buffer.WriteString(`"address":`)
+		if bs, err := al.Address.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+		buffer.WriteString(`,`)
+		buffer.WriteString(`"storageKeys":[`)
+		for j, key := range al.StorageKeys {
+			if j > 0 {
+				buffer.WriteString(`,`)
+			}
+			buffer.WriteString(`"`)
+			buffer.WriteString(hex.EncodeToString(key[:]))
+			buffer.WriteString(`"`)
+		}
+		buffer.WriteString(`]`)
+		buffer.WriteString(`}`)
+	}
+	buffer.WriteString(`]`)
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"trace":`)
+	if bs, err := json.Marshal(tx.Trace); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package solidity
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+)
+
+func TestCallContractWriteToReadFromRoundTrip(t *testing.T) {
+	want := &CallContract{
+		Seq_:  7,
+		From_: common.Address{1},
+		To:    common.Address{2},
+		Value: amount.NewCoinAmount(0, 100),
+		Input: []byte{0x01, 0x02, 0x03},
+		AccessList: AccessList{
+			{
+				Address:     common.Address{3},
+				StorageKeys: []hash.Hash256{hash.Hash([]byte("slot-a")), hash.Hash([]byte("slot-b"))},
+			},
+			{
+				Address:     common.Address{4},
+				StorageKeys: nil,
+			},
+		},
+		Trace: TraceFlags{Enabled: true, EmitOnSuccess: true},
+	}
+
+	var buf bytes.Buffer
+	if _, err := want.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	got := &CallContract{}
+	if _, err := got.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	if got.Seq_ != want.Seq_ || got.From_ != want.From_ || got.To != want.To {
+		t.Fatalf("scalar fields = %+v, want %+v", got, want)
+	}
+	if !amountEqual(got.Value, want.Value) {
+		t.Fatalf("Value = %v, want %v", got.Value, want.Value)
+	}
+	if !bytes.Equal(got.Input, want.Input) {
+		t.Fatalf("Input = %x, want %x", got.Input, want.Input)
+	}
+	if got.Trace != want.Trace {
+		t.Fatalf("Trace = %+v, want %+v", got.Trace, want.Trace)
+	}
+
+	if len(got.AccessList) != len(want.AccessList) {
+		t.Fatalf("AccessList length = %d, want %d", len(got.AccessList), len(want.AccessList))
+	}
+	for i, al := range want.AccessList {
+		if got.AccessList[i].Address != al.Address {
+			t.Fatalf("AccessList[%d].Address = %v, want %v", i, got.AccessList[i].Address, al.Address)
+		}
+		if len(got.AccessList[i].StorageKeys) != len(al.StorageKeys) {
+			t.Fatalf("AccessList[%d].StorageKeys length = %d, want %d", i, len(got.AccessList[i].StorageKeys), len(al.StorageKeys))
+		}
+		for j, key := range al.StorageKeys {
+			if got.AccessList[i].StorageKeys[j] != key {
+				t.Fatalf("AccessList[%d].StorageKeys[%d] = %x, want %x", i, j, got.AccessList[i].StorageKeys[j], key)
+			}
+		}
+	}
+}
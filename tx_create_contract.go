@@ -97,18 +97,17 @@ func init() {
 			return nil, ErrExistAccountName
 		}
 
-		statedb := &StateDB{
-			Context: ctx,
-			Coord:   coord,
-		}
+		statedb := NewStateDB(ctx, coord)
+		traceEnabled := tx.Trace.Enabled
 		logconfig := &vm.LogConfig{
 			DisableMemory: false,
 			DisableStack:  false,
-			Debug:         false,
+			Debug:         traceEnabled,
 		}
 		vmCfg := vm.Config{
-			Tracer: vm.NewStructLogger(logconfig),
-			Debug:  false,
+			Tracer:      vm.NewStructLogger(logconfig),
+			Debug:       traceEnabled,
+			Precompiles: DefaultPrecompiles,
 		}
 		vctx := vm.Context{
 			CanTransfer: CanTransfer,
@@ -119,11 +118,13 @@ func init() {
 			Time:        big.NewInt(time.Now().Unix()),
 			Difficulty:  new(big.Int),
 		}
+		statedb.Prepare(tx.From(), common.Address{}, &contAddr, DefaultPrecompiles.Addresses(), nil)
 		evm := vm.NewEVM(vctx, statedb, vmCfg)
 		code, err := evm.Create(vm.AccountRef(tx.From()), contAddr, tx.Name, append(tx.Code, tx.Params...), amount.NewCoinAmount(0, 0))
-		if err != nil {
-			return nil, err
+		if terr := traceExecution(ctx, coord, tx.Trace.Options(), vmCfg.Tracer.(*vm.StructLogger), err); terr != nil {
+			return nil, terr
 		}
+		statedb.Finalise()
 		ctx.Commit(sn)
 		return code, nil
 	})
@@ -138,6 +139,11 @@ type CreateContract struct {
 	Name   string
 	Code   []byte
 	Params []byte
+
+	// Trace toggles the opcode trace for this call; the Tracer implementation
+	// itself always comes from SetDefaultTracer, since a Tracer is Go-level
+	// behavior and cannot travel over the wire.
+	Trace TraceFlags
 }
 
 // IsUTXO returns false
@@ -193,6 +199,11 @@ func (tx *CreateContract) WriteTo(w io.Writer) (int64, error) {
 	} else {
 		wrote += n
 	}
+	if n, err := w.Write([]byte{tx.Trace.encode()}); err != nil {
+		return wrote, err
+	} else {
+		wrote += int64(n)
+	}
 	return wrote, nil
 }
 
@@ -233,6 +244,15 @@ func (tx *CreateContract) ReadFrom(r io.Reader) (int64, error) {
 		read += n
 		tx.Params = bs
 	}
+	{
+		var buf [1]byte
+		if n, err := io.ReadFull(r, buf[:]); err != nil {
+			return read, err
+		} else {
+			read += int64(n)
+		}
+		tx.Trace = decodeTraceFlags(buf[0])
+	}
 	return read, nil
 }
 
@@ -285,6 +305,13 @@ func (tx *CreateContract) MarshalJSON() ([]byte, error) {
 		buffer.WriteString(hex.EncodeToString(tx.Params))
 		buffer.WriteString(`"`)
 	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"trace":`)
+	if bs, err := json.Marshal(tx.Trace); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
 	buffer.WriteString(`}`)
 	return buffer.Bytes(), nil
 }